@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// BodyCaptureConfig controls how request/response bodies and headers are
+// captured for logging.
+type BodyCaptureConfig struct {
+	MaxBodyBytes  int64
+	ContentTypes  []string // allowlist, e.g. "application/json", "text/*"; empty means allow all
+	RedactHeaders map[string]bool
+}
+
+func newBodyCaptureConfig(maxBodyBytes int64, contentTypesCSV, redactHeadersCSV string) *BodyCaptureConfig {
+	cfg := &BodyCaptureConfig{
+		MaxBodyBytes:  maxBodyBytes,
+		RedactHeaders: make(map[string]bool),
+	}
+	for _, ct := range splitCSV(contentTypesCSV) {
+		cfg.ContentTypes = append(cfg.ContentTypes, strings.ToLower(ct))
+	}
+	for _, h := range splitCSV(redactHeadersCSV) {
+		cfg.RedactHeaders[strings.ToLower(h)] = true
+	}
+	return cfg
+}
+
+func splitCSV(s string) []string {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// allowContentType reports whether contentType passes the allowlist. An
+// empty allowlist allows everything. Matching supports a "type/*" wildcard.
+func (c *BodyCaptureConfig) allowContentType(contentType string) bool {
+	if len(c.ContentTypes) == 0 {
+		return true
+	}
+	contentType = strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	for _, allowed := range c.ContentTypes {
+		if allowed == contentType {
+			return true
+		}
+		if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedHeaders returns a loggable copy of h with configured header values
+// replaced by "REDACTED".
+func (c *BodyCaptureConfig) redactedHeaders(h http.Header) map[string][]string {
+	out := make(map[string][]string, len(h))
+	for key, values := range h {
+		if c.RedactHeaders[strings.ToLower(key)] {
+			out[key] = []string{"REDACTED"}
+			continue
+		}
+		out[key] = values
+	}
+	return out
+}
+
+// captureBody returns a loggable string for up to c.MaxBodyBytes of body,
+// transparently gzip-decoding it when contentEncoding is "gzip", along with
+// whether it was truncated and the original (pre-cap) size in bytes. body
+// itself is never modified; callers are responsible for restoring it for
+// forwarding.
+func (c *BodyCaptureConfig) captureBody(body []byte, contentEncoding string) (logged string, truncated bool, origSize int64) {
+	origSize = int64(len(body))
+
+	capped := body
+	truncated = origSize > c.MaxBodyBytes
+	if truncated {
+		capped = body[:c.MaxBodyBytes]
+	}
+
+	if strings.EqualFold(contentEncoding, "gzip") {
+		if decoded, err := gunzip(capped); err == nil {
+			return string(decoded), truncated, origSize
+		}
+		// Could not decode (e.g. truncated mid-stream); fall back to raw bytes.
+	}
+
+	return string(capped), truncated, origSize
+}
+
+func gunzip(b []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// cappedTeeReadCloser tees reads into a bounded buffer (up to max bytes) so a
+// response body can be streamed to the client while still capturing a capped
+// copy for logging. onClose fires once, when the wrapped body is closed.
+type cappedTeeReadCloser struct {
+	underlying io.ReadCloser
+	buf        bytes.Buffer
+	max        int64
+	origSize   int64
+	onClose    func(captured []byte, truncated bool, origSize int64)
+}
+
+func newCappedTeeReadCloser(underlying io.ReadCloser, max int64, onClose func([]byte, bool, int64)) *cappedTeeReadCloser {
+	return &cappedTeeReadCloser{underlying: underlying, max: max, onClose: onClose}
+}
+
+func (c *cappedTeeReadCloser) Read(p []byte) (int, error) {
+	n, err := c.underlying.Read(p)
+	if n > 0 {
+		c.origSize += int64(n)
+		if room := c.max - int64(c.buf.Len()); room > 0 {
+			if int64(n) < room {
+				c.buf.Write(p[:n])
+			} else {
+				c.buf.Write(p[:room])
+			}
+		}
+	}
+	return n, err
+}
+
+func (c *cappedTeeReadCloser) Close() error {
+	err := c.underlying.Close()
+	c.onClose(c.buf.Bytes(), c.origSize > int64(c.buf.Len()), c.origSize)
+	return err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"testing"
+)
+
+func TestBodyCaptureConfigCaptureBody(t *testing.T) {
+	cfg := newBodyCaptureConfig(8, "", "")
+
+	t.Run("under cap", func(t *testing.T) {
+		logged, truncated, origSize := cfg.captureBody([]byte("short"), "")
+		if logged != "short" || truncated || origSize != 5 {
+			t.Fatalf("got (%q, %v, %d)", logged, truncated, origSize)
+		}
+	})
+
+	t.Run("truncates over cap", func(t *testing.T) {
+		body := []byte("this is far too long")
+		logged, truncated, origSize := cfg.captureBody(body, "")
+		if logged != "this is " || !truncated || origSize != int64(len(body)) {
+			t.Fatalf("got (%q, %v, %d)", logged, truncated, origSize)
+		}
+	})
+
+	t.Run("decodes gzip", func(t *testing.T) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte("gzipped"))
+		gz.Close()
+
+		// Use a cap large enough that the gzip stream isn't truncated before
+		// decoding; captureBody caps the raw bytes first, same as the proxy
+		// path does for the real wire format.
+		uncapped := newBodyCaptureConfig(int64(buf.Len()), "", "")
+		logged, truncated, _ := uncapped.captureBody(buf.Bytes(), "gzip")
+		if logged != "gzipped" || truncated {
+			t.Fatalf("got (%q, %v)", logged, truncated)
+		}
+	})
+
+	t.Run("falls back to raw bytes on bad gzip", func(t *testing.T) {
+		logged, _, _ := cfg.captureBody([]byte("not gzip"), "gzip")
+		if logged != "not gzip" {
+			t.Fatalf("got %q, want raw fallback", logged)
+		}
+	})
+}
+
+func TestBodyCaptureConfigAllowContentType(t *testing.T) {
+	cfg := newBodyCaptureConfig(64, "application/json,text/*", "")
+
+	cases := []struct {
+		contentType string
+		want        bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"text/plain", true},
+		{"image/png", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if got := cfg.allowContentType(c.contentType); got != c.want {
+			t.Errorf("allowContentType(%q) = %v, want %v", c.contentType, got, c.want)
+		}
+	}
+
+	t.Run("empty allowlist allows everything", func(t *testing.T) {
+		open := newBodyCaptureConfig(64, "", "")
+		if !open.allowContentType("anything/at-all") {
+			t.Fatal("expected empty allowlist to allow everything")
+		}
+	})
+}
+
+func TestBodyCaptureConfigRedactedHeaders(t *testing.T) {
+	cfg := newBodyCaptureConfig(64, "", "Authorization,Cookie")
+	h := http.Header{
+		"Authorization": []string{"Bearer secret"},
+		"Cookie":        []string{"session=abc"},
+		"X-Request-Id":  []string{"123"},
+	}
+
+	out := cfg.redactedHeaders(h)
+	if out["Authorization"][0] != "REDACTED" || out["Cookie"][0] != "REDACTED" {
+		t.Fatalf("expected Authorization/Cookie to be redacted, got %v", out)
+	}
+	if out["X-Request-Id"][0] != "123" {
+		t.Fatalf("expected X-Request-Id to pass through, got %v", out)
+	}
+}
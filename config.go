@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yukikun-dev/logger/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// UpstreamConfig describes a single backend in the pool.
+type UpstreamConfig struct {
+	URL string `yaml:"url"`
+	// Type tags the upstream (e.g. "thirdparty") so routing rules such as
+	// ThirdpartyOnlyDomains can restrict selection to a subset of the pool.
+	Type string `yaml:"type,omitempty"`
+}
+
+// Config is the YAML-loaded pool configuration for --config.
+type Config struct {
+	Upstreams []UpstreamConfig `yaml:"upstreams"`
+
+	// CheckURL is appended to each upstream's base URL and polled with a GET
+	// request to determine health. Defaults to "/" when empty.
+	CheckURL string `yaml:"check_url"`
+	// CheckInterval is how often each upstream is polled.
+	CheckInterval time.Duration `yaml:"check_interval"`
+	// CooldownPeriod is how long an upstream is skipped after a failed check.
+	CooldownPeriod time.Duration `yaml:"cooldown_period"`
+
+	// BypassDomains lists request hosts that should never use the pool and
+	// instead be proxied directly to themselves.
+	BypassDomains []string `yaml:"bypass_domains"`
+	// ThirdpartyOnlyDomains lists request hosts that must only be routed to
+	// upstreams with Type == "thirdparty".
+	ThirdpartyOnlyDomains []string `yaml:"thirdparty_only_domains"`
+
+	// Sinks declares where log entries are written. When empty, New falls
+	// back to its historical stdout+rotating-file default.
+	Sinks []logging.SinkConfig `yaml:"sinks"`
+}
+
+// LoadConfig reads and parses a pool configuration file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+
+	if len(cfg.Upstreams) == 0 {
+		return nil, errors.New("config must declare at least one upstream")
+	}
+	if cfg.CheckURL == "" {
+		cfg.CheckURL = "/"
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = 10 * time.Second
+	}
+	if cfg.CooldownPeriod <= 0 {
+		cfg.CooldownPeriod = 30 * time.Second
+	}
+
+	return &cfg, nil
+}
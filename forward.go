@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yukikun-dev/logger/internal/logging"
+	"go.uber.org/zap"
+)
+
+// hopHeaders are stripped from both the outgoing request and the incoming
+// response: they're meaningful only between adjacent connections, not
+// end-to-end, and forwarding them verbatim can desync keep-alive state
+// between hops. httputil.ReverseProxy does this for free; the forward proxy
+// has to do it itself.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection", // non-standard, but still sent by some clients
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopByHopHeaders strips the standard hop-by-hop set from h, plus any
+// header named in h's own Connection value.
+func removeHopByHopHeaders(h http.Header) {
+	if connection := h.Get("Connection"); connection != "" {
+		for _, name := range strings.Split(connection, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// newForwardProxyHandler returns an http.Handler implementing a standard
+// HTTP forward proxy: CONNECT requests are tunneled via a hijacked raw
+// connection (for HTTPS), and plain HTTP requests are replayed through
+// client and logged the same way the reverse-proxy Director is, honoring
+// capture's size cap, content-type allowlist, and header redaction.
+func newForwardProxyHandler(logger *logging.Logger, capture *BodyCaptureConfig) http.Handler {
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+				DualStack: true,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+		// Forward proxies must not follow redirects themselves; the client
+		// on the other end of the tunnel expects to see them.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodConnect {
+			handleConnect(w, r, logger)
+			return
+		}
+		handleForwardHTTP(w, r, client, logger, capture)
+	})
+}
+
+// handleConnect hijacks the client connection and pumps bytes between it and
+// a dialed connection to the requested host, for HTTPS tunneling.
+func handleConnect(w http.ResponseWriter, r *http.Request, logger *logging.Logger) {
+	start := time.Now()
+
+	upstreamConn, err := net.DialTimeout("tcp", r.Host, 10*time.Second)
+	if err != nil {
+		logger.Error("CONNECT dial failed", zap.String("host", r.Host), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstreamConn.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		logger.Error("CONNECT not supported: ResponseWriter does not implement Hijacker")
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+
+	clientConn, _, err := hijacker.Hijack()
+	if err != nil {
+		logger.Error("CONNECT hijack failed", zap.String("host", r.Host), zap.Error(err))
+		return
+	}
+	defer clientConn.Close()
+
+	if _, err := clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		logger.Error("CONNECT write failed", zap.String("host", r.Host), zap.Error(err))
+		return
+	}
+
+	logger.Info("CONNECT tunnel established", zap.String("host", r.Host))
+
+	done := make(chan struct{}, 2)
+	go pumpConn(upstreamConn, clientConn, done)
+	go pumpConn(clientConn, upstreamConn, done)
+	<-done
+	<-done
+
+	logger.Info("CONNECT tunnel closed", zap.String("host", r.Host), zap.Duration("elapsed", time.Since(start)))
+}
+
+func pumpConn(dst io.Writer, src io.Reader, done chan<- struct{}) {
+	io.Copy(dst, src)
+	if c, ok := dst.(interface{ CloseWrite() error }); ok {
+		c.CloseWrite()
+	}
+	done <- struct{}{}
+}
+
+// handleForwardHTTP proxies a plain HTTP request through client, logging
+// method, URL, headers, and body the same way the reverse-proxy Director
+// does: capped to capture.MaxBodyBytes, filtered by capture's content-type
+// allowlist, and with capture's redacted headers.
+func handleForwardHTTP(w http.ResponseWriter, r *http.Request, client *http.Client, logger *logging.Logger, capture *BodyCaptureConfig) {
+	start := time.Now()
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Error("Error reading body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outReq, err := http.NewRequest(r.Method, r.URL.String(), bytes.NewReader(bodyBytes))
+	if err != nil {
+		logger.Error("building forward request failed", zap.String("url", r.URL.String()), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	outReq.Header = r.Header.Clone()
+	removeHopByHopHeaders(outReq.Header)
+
+	if ce := logger.Check(zap.InfoLevel, "Received request"); ce != nil {
+		fields := []zap.Field{
+			zap.String("method", r.Method), zap.String("url", r.URL.String()),
+			zap.Any("headers", capture.redactedHeaders(r.Header)),
+		}
+		if capture.allowContentType(r.Header.Get("Content-Type")) {
+			logged, truncated, origSize := capture.captureBody(bodyBytes, r.Header.Get("Content-Encoding"))
+			fields = append(fields, zap.String("body", logged), zap.Bool("truncated", truncated), zap.Int64("orig_size", origSize))
+		}
+		ce.Write(fields...)
+	}
+
+	resp, err := client.Do(outReq)
+	if err != nil {
+		logger.Error("forward request failed", zap.String("url", r.URL.String()), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer func() { resp.Body.Close() }()
+
+	removeHopByHopHeaders(resp.Header)
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	if ce := logger.Check(zap.InfoLevel, "Received response"); ce != nil {
+		baseFields := []zap.Field{
+			zap.String("status", resp.Status), zap.String("url", r.URL.String()),
+			zap.String("method", r.Method), zap.Any("headers", capture.redactedHeaders(resp.Header)),
+			zap.Duration("elapsed", time.Since(start)),
+		}
+
+		if capture.allowContentType(resp.Header.Get("Content-Type")) {
+			contentEncoding := resp.Header.Get("Content-Encoding")
+			resp.Body = newCappedTeeReadCloser(resp.Body, capture.MaxBodyBytes, func(captured []byte, truncated bool, origSize int64) {
+				logged, _, _ := capture.captureBody(captured, contentEncoding)
+				logger.Info("Received response",
+					append(baseFields, zap.String("body", logged), zap.Bool("truncated", truncated), zap.Int64("orig_size", origSize))...)
+			})
+		} else {
+			ce.Write(baseFields...)
+		}
+	}
+
+	io.Copy(w, resp.Body)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRemoveHopByHopHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Connection", "keep-alive, X-Custom-Hop")
+	h.Set("Proxy-Connection", "keep-alive")
+	h.Set("Keep-Alive", "timeout=5")
+	h.Set("Te", "trailers")
+	h.Set("Transfer-Encoding", "chunked")
+	h.Set("X-Custom-Hop", "should be removed via Connection")
+	h.Set("X-Request-Id", "123")
+
+	removeHopByHopHeaders(h)
+
+	for _, name := range []string{"Connection", "Proxy-Connection", "Keep-Alive", "Te", "Transfer-Encoding", "X-Custom-Hop"} {
+		if h.Get(name) != "" {
+			t.Errorf("expected %q to be stripped, got %q", name, h.Get(name))
+		}
+	}
+	if h.Get("X-Request-Id") != "123" {
+		t.Fatalf("expected end-to-end header X-Request-Id to survive, got %q", h.Get("X-Request-Id"))
+	}
+}
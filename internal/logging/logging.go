@@ -0,0 +1,114 @@
+// Package logging wires together the proxy's log output: a *zap.Logger
+// backed by one or more pluggable Sinks, combined via zapcore.NewTee.
+package logging
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Logger is a thin wrapper around *zap.Logger that exposes Check so hot
+// proxy-path call sites (the Director, ModifyResponse, ErrorHandler) can
+// skip building log fields entirely when the level is disabled.
+type Logger struct {
+	z *zap.Logger
+}
+
+// Check reports whether logging at lvl is enabled, returning a
+// *zapcore.CheckedEntry to Write fields to if so, or nil otherwise.
+func (l *Logger) Check(lvl zapcore.Level, msg string) *zapcore.CheckedEntry {
+	return l.z.Check(lvl, msg)
+}
+
+// Info, Warn and Error are Check-gated convenience loggers for call sites
+// that don't need to avoid constructing their fields on the disabled path.
+func (l *Logger) Info(msg string, fields ...zap.Field) {
+	if ce := l.z.Check(zap.InfoLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+func (l *Logger) Warn(msg string, fields ...zap.Field) {
+	if ce := l.z.Check(zap.WarnLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+func (l *Logger) Error(msg string, fields ...zap.Field) {
+	if ce := l.z.Check(zap.ErrorLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// Options configures New.
+type Options struct {
+	// Level is the minimum enabled level, e.g. "debug", "info", "warn", "error".
+	Level string
+	// Format selects the encoding used by sinks that don't declare their own
+	// (currently: stdout). "console" (default) or "json".
+	Format string
+	// Sinks declares the destinations to tee log output to. If empty, New
+	// falls back to the historical default: a console-encoded stdout sink
+	// plus a JSON-encoded rotating file sink at logger.log.
+	Sinks []SinkConfig
+}
+
+// New builds a Logger from opts, combining every configured Sink's core via
+// zapcore.NewTee.
+func New(opts Options) (*Logger, error) {
+	level, err := zapcore.ParseLevel(levelOrDefault(opts.Level))
+	if err != nil {
+		return nil, fmt.Errorf("parsing log level: %w", err)
+	}
+
+	sinkConfigs := opts.Sinks
+	if len(sinkConfigs) == 0 {
+		sinkConfigs = []SinkConfig{
+			{Type: "stdout"},
+			{Type: "file", Path: "logger.log"},
+		}
+	}
+
+	cores := make([]zapcore.Core, 0, len(sinkConfigs))
+	for _, sc := range sinkConfigs {
+		sink, err := newSink(sc, opts.Format)
+		if err != nil {
+			return nil, fmt.Errorf("configuring %s sink: %w", sc.Type, err)
+		}
+		cores = append(cores, sink.Core(level))
+	}
+
+	return &Logger{z: zap.New(zapcore.NewTee(cores...))}, nil
+}
+
+func levelOrDefault(level string) string {
+	if level == "" {
+		return "info"
+	}
+	return level
+}
+
+func consoleEncoder() zapcore.Encoder {
+	cfg := zap.NewDevelopmentEncoderConfig()
+	cfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	cfg.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.Format("2006-01-02 15:04:05"))
+	}
+	return zapcore.NewConsoleEncoder(cfg)
+}
+
+func jsonEncoder() zapcore.Encoder {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	return zapcore.NewJSONEncoder(cfg)
+}
+
+func encoderFor(format string) zapcore.Encoder {
+	if format == "json" {
+		return jsonEncoder()
+	}
+	return consoleEncoder()
+}
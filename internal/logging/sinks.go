@@ -0,0 +1,231 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// SinkConfig is the YAML shape of a single entry under a pool config's
+// `sinks:` list.
+type SinkConfig struct {
+	Type string `yaml:"type"`
+
+	// file
+	Path string `yaml:"path,omitempty"`
+
+	// syslog
+	Network string `yaml:"network,omitempty"` // "udp" or "tcp"
+	Addr    string `yaml:"addr,omitempty"`
+	Tag     string `yaml:"tag,omitempty"`
+
+	// loki
+	URL    string            `yaml:"url,omitempty"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// Sink produces the zapcore.Core a log entry is written through.
+type Sink interface {
+	Core(level zapcore.Level) zapcore.Core
+}
+
+func newSink(cfg SinkConfig, format string) (Sink, error) {
+	switch cfg.Type {
+	case "stdout", "":
+		return &stdoutSink{format: format}, nil
+	case "file":
+		path := cfg.Path
+		if path == "" {
+			path = "logger.log"
+		}
+		return &fileSink{path: path}, nil
+	case "syslog":
+		return newSyslogSink(cfg)
+	case "loki":
+		return newLokiSink(cfg)
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+	}
+}
+
+// stdoutSink writes to os.Stdout using the console or JSON encoder.
+type stdoutSink struct {
+	format string
+}
+
+func (s *stdoutSink) Core(level zapcore.Level) zapcore.Core {
+	return zapcore.NewCore(encoderFor(s.format), zapcore.AddSync(os.Stdout), level)
+}
+
+// fileSink writes JSON lines to a lumberjack-rotated file.
+type fileSink struct {
+	path string
+}
+
+func (s *fileSink) Core(level zapcore.Level) zapcore.Core {
+	return zapcore.NewCore(jsonEncoder(), zapcore.AddSync(&lumberjack.Logger{Filename: s.path}), level)
+}
+
+// syslogSink ships JSON-framed entries to a syslog collector over UDP or TCP
+// using an RFC 5424-shaped header.
+type syslogSink struct {
+	writer *rfc5424Writer
+}
+
+func newSyslogSink(cfg SinkConfig) (Sink, error) {
+	network := cfg.Network
+	if network == "" {
+		network = "udp"
+	}
+	if cfg.Addr == "" {
+		return nil, fmt.Errorf("syslog sink requires addr")
+	}
+	tag := cfg.Tag
+	if tag == "" {
+		tag = "logger"
+	}
+
+	conn, err := net.Dial(network, cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("dialing syslog at %s: %w", cfg.Addr, err)
+	}
+
+	return &syslogSink{writer: &rfc5424Writer{conn: conn, tag: tag}}, nil
+}
+
+func (s *syslogSink) Core(level zapcore.Level) zapcore.Core {
+	return zapcore.NewCore(jsonEncoder(), zapcore.AddSync(s.writer), level)
+}
+
+// rfc5424Writer prefixes each write with a minimal RFC 5424 syslog header
+// and sends it over an already-dialed connection.
+type rfc5424Writer struct {
+	conn net.Conn
+	tag  string
+}
+
+func (w *rfc5424Writer) Write(p []byte) (int, error) {
+	hostname, _ := os.Hostname()
+	header := fmt.Sprintf("<%d>1 %s %s %s %d - - ",
+		int(syslog.LOG_INFO|syslog.LOG_USER), time.Now().UTC().Format(time.RFC3339), hostname, w.tag, os.Getpid())
+
+	if _, err := w.conn.Write(append([]byte(header), p...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+const (
+	lokiQueueSize     = 1024
+	lokiBatchSize     = 100
+	lokiFlushInterval = time.Second
+)
+
+// lokiSink batches entries as Loki's push API shape and ships them to a
+// /loki/api/v1/push endpoint from a background goroutine. Write only
+// enqueues onto a bounded channel, so a slow or unreachable Loki never
+// blocks the logging call site - which, via Director/ModifyResponse, is the
+// proxied request itself.
+type lokiSink struct {
+	url    string
+	labels map[string]string
+	client *http.Client
+	lines  chan string
+}
+
+func newLokiSink(cfg SinkConfig) (Sink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("loki sink requires url")
+	}
+	s := &lokiSink{
+		url:    cfg.URL,
+		labels: cfg.Labels,
+		client: &http.Client{Timeout: 5 * time.Second},
+		lines:  make(chan string, lokiQueueSize),
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *lokiSink) Core(level zapcore.Level) zapcore.Core {
+	return zapcore.NewCore(jsonEncoder(), zapcore.AddSync(s), level)
+}
+
+// Write enqueues p for the background flusher. The queue is bounded; once
+// full, lines are dropped rather than applying backpressure to the caller.
+func (s *lokiSink) Write(p []byte) (int, error) {
+	line := string(bytes.TrimRight(p, "\n"))
+	select {
+	case s.lines <- line:
+	default:
+		// Queue full: drop rather than block the request goroutine.
+	}
+	return len(p), nil
+}
+
+// run batches queued lines and flushes them on a timer or once a batch fills
+// up, whichever comes first.
+func (s *lokiSink) run() {
+	batch := make([]string, 0, lokiBatchSize)
+	ticker := time.NewTicker(lokiFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, line)
+			if len(batch) >= lokiBatchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush POSTs lines to Loki as a single push request. Errors are best-effort:
+// a failed Loki push must not affect the proxy.
+func (s *lokiSink) flush(lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	values := make([][]string, len(lines))
+	for i, line := range lines {
+		values[i] = []string{fmt.Sprintf("%d", time.Now().UnixNano()), line}
+	}
+	payload := map[string]interface{}{
+		"streams": []map[string]interface{}{
+			{"stream": s.labels, "values": values},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+}
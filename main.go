@@ -2,59 +2,79 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/yukikun-dev/logger/internal/logging"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 func init() {
+	// Adding the record/replay subcommands flips cobra's default Args
+	// validator into subcommand-checking mode; rootCmd must still accept
+	// its own positional target URLs (validated in PreRunE).
+	rootCmd.Args = cobra.ArbitraryArgs
+
 	rootCmd.PersistentFlags().StringP("port", "p", "9090", "port to run the logging server on")
 	rootCmd.PersistentFlags().BoolP("listen", "l", false, "listen on all interfaces")
+	rootCmd.Flags().String("config", "", "path to a YAML pool config (upstreams, health checks, bypass/thirdparty rules); overrides positional target URLs")
+	rootCmd.Flags().String("mode", "reverse", "proxy mode: reverse (default, proxies to a pool of targets) or forward (standard HTTP/CONNECT forward proxy)")
+	rootCmd.Flags().Int64("max-body-bytes", 64*1024, "maximum number of request/response body bytes to capture in logs")
+	rootCmd.Flags().String("log-content-types", "", "comma-separated content-type allowlist for body logging, e.g. application/json,text/*")
+	rootCmd.Flags().String("redact-headers", "", "comma-separated header names to redact in logs, e.g. Authorization,Cookie")
+	rootCmd.Flags().Int64("max-frame-bytes", 0, "maximum WebSocket frame payload bytes to include in logs (0 logs opcode/length only)")
+	rootCmd.Flags().String("log-level", "info", "minimum log level: debug, info, warn, or error")
+	rootCmd.Flags().String("log-format", "console", "encoding for sinks without their own format: json or console")
+	rootCmd.Flags().StringArray("log-sink", nil, "log sink to enable (stdout, file); repeatable. Detailed sinks (syslog, loki) require --config's sinks list. Defaults to stdout+file when unset")
 }
 
-func getLogger() *zap.SugaredLogger {
-	encoderCfg := zap.NewProductionEncoderConfig()
-	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
-	jsonEncoder := zapcore.NewJSONEncoder(encoderCfg)
+// loggerOptionsFromCmd resolves logging.Options from flags and, if present,
+// the pool config's sinks list (which takes priority over --log-sink).
+func loggerOptionsFromCmd(cmd *cobra.Command, cfg *Config) logging.Options {
+	level, _ := cmd.Flags().GetString("log-level")
+	format, _ := cmd.Flags().GetString("log-format")
+	opts := logging.Options{Level: level, Format: format}
+
+	if cfg != nil && len(cfg.Sinks) > 0 {
+		opts.Sinks = cfg.Sinks
+		return opts
+	}
 
-	encoderCfg = zap.NewDevelopmentEncoderConfig()
-	encoderCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	encoderCfg.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-		enc.AppendString(t.Format("2006-01-02 15:04:05"))
+	sinkNames, _ := cmd.Flags().GetStringArray("log-sink")
+	for _, name := range sinkNames {
+		opts.Sinks = append(opts.Sinks, logging.SinkConfig{Type: name})
 	}
-	consoleEncoder := zapcore.NewConsoleEncoder(encoderCfg)
-
-	fileCore := zapcore.NewCore(
-		jsonEncoder,
-		zapcore.AddSync(&lumberjack.Logger{
-			Filename: "logger.log",
-		}),
-		zap.InfoLevel,
-	)
-
-	stdoutCore := zapcore.NewCore(
-		consoleEncoder,
-		zapcore.AddSync(os.Stdout),
-		zap.InfoLevel,
-	)
-
-	core := zapcore.NewTee(fileCore, stdoutCore)
-	logger := zap.New(core)
-
-	return logger.Sugar()
+	return opts
 }
+
+// poolConfigFromCmd resolves the pool configuration either from --config or,
+// for the common case of a handful of target URLs, from positional args.
+func poolConfigFromCmd(cmd *cobra.Command, args []string) (*Config, error) {
+	configPath, _ := cmd.Flags().GetString("config")
+	if configPath != "" {
+		return LoadConfig(configPath)
+	}
+
+	cfg := &Config{
+		CheckURL:       "/",
+		CheckInterval:  10 * time.Second,
+		CooldownPeriod: 30 * time.Second,
+	}
+	for _, target := range args {
+		cfg.Upstreams = append(cfg.Upstreams, UpstreamConfig{URL: target})
+	}
+	return cfg, nil
+}
+
 func singleJoiningSlash(a, b string) string {
 	aslash := strings.HasSuffix(a, "/")
 	bslash := strings.HasPrefix(b, "/")
@@ -68,76 +88,227 @@ func singleJoiningSlash(a, b string) string {
 }
 
 var rootCmd = &cobra.Command{
-	Use:   "logger [target URL]",
+	Use:   "logger [target URL]...",
 	Short: "logger is a simple http proxy server that logs all requests",
-	Long:  `logger is a simple http proxy server that logs all requests`,
+	Long: `logger is a simple http proxy server that logs all requests.
+
+It accepts one or more target URLs to load-balance across, or a --config
+YAML file describing a pool of upstreams along with health-check and
+per-domain routing rules.`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) < 1 {
-			return errors.New("you must provide a target URL")
+		mode, _ := cmd.Flags().GetString("mode")
+		if mode == "forward" {
+			return nil
+		}
+		configPath, _ := cmd.Flags().GetString("config")
+		if configPath == "" && len(args) < 1 {
+			return errors.New("you must provide at least one target URL, or --config")
 		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		logger := getLogger()
+		port := cmd.Flag("port").Value.String()
+		listenAll := cmd.Flag("listen").Value.String() == "true"
 
-		target, err := url.Parse(args[0])
-		if err != nil {
-			panic(err)
+		mode, _ := cmd.Flags().GetString("mode")
+		if mode == "forward" {
+			logger, err := logging.New(loggerOptionsFromCmd(cmd, nil))
+			if err != nil {
+				panic(err)
+			}
+			maxBodyBytes, _ := cmd.Flags().GetInt64("max-body-bytes")
+			logContentTypes, _ := cmd.Flags().GetString("log-content-types")
+			redactHeaders, _ := cmd.Flags().GetString("redact-headers")
+			capture := newBodyCaptureConfig(maxBodyBytes, logContentTypes, redactHeaders)
+
+			runForwardProxy(logger, capture, port, listenAll)
+			return
 		}
-		logger.Info("proxying to " + target.String())
 
-		proxy := &httputil.ReverseProxy{
-			Director: func(req *http.Request) {
+		runReverseProxyServer(cmd, args, nil)
+	},
+}
+
+// runReverseProxyServer builds and serves the pool-backed reverse proxy:
+// health-checked upstream selection, body-capture logging, and WebSocket
+// frame logging. When recorder is non-nil, every round-trip is additionally
+// appended to its journal (used by the `record` subcommand).
+func runReverseProxyServer(cmd *cobra.Command, args []string, recorder *Recorder) {
+	port := cmd.Flag("port").Value.String()
+	listenAll := cmd.Flag("listen").Value.String() == "true"
+
+	cfg, err := poolConfigFromCmd(cmd, args)
+	if err != nil {
+		panic(err)
+	}
+
+	logger, err := logging.New(loggerOptionsFromCmd(cmd, cfg))
+	if err != nil {
+		panic(err)
+	}
+
+	pool, err := NewPool(cfg, logger)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.Start(ctx)
+
+	for _, u := range cfg.Upstreams {
+		logger.Info("proxying to " + u.URL)
+	}
+
+	maxBodyBytes, _ := cmd.Flags().GetInt64("max-body-bytes")
+	logContentTypes, _ := cmd.Flags().GetString("log-content-types")
+	redactHeaders, _ := cmd.Flags().GetString("redact-headers")
+	capture := newBodyCaptureConfig(maxBodyBytes, logContentTypes, redactHeaders)
+
+	maxFrameBytes, _ := cmd.Flags().GetInt64("max-frame-bytes")
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			if isStreamingRequest(req) {
+				if ce := logger.Check(zap.InfoLevel, "Received request"); ce != nil {
+					ce.Write(zap.String("method", req.Method), zap.String("url", req.URL.String()), zap.Bool("streaming", true))
+				}
+			} else {
 				bodyBytes, err := io.ReadAll(req.Body)
 				if err != nil {
 					logger.Error("Error reading body", zap.Error(err))
 					return
 				}
-				logger.Infow("Received request", "method", req.Method, "url", req.URL.String(), "body", string(bodyBytes))
 				req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
-				req.URL.Scheme = target.Scheme
-				req.URL.Host = target.Host
-				req.URL.Path = singleJoiningSlash(target.Path, req.URL.Path)
-				req.Host = target.Host
-				logger.Info("Forwarding request to ", req.URL.String())
-			},
-			ModifyResponse: func(res *http.Response) error {
-				logger.Infow("Received response", "status", res.Status, "url", res.Request.URL.String(), "method", res.Request.Method)
-				return nil
-			},
-			ErrorHandler: func(writer http.ResponseWriter, request *http.Request, e error) {
-				logger.Error("proxy error", zap.Error(e))
-			},
-			Transport: &http.Transport{
-				Proxy: http.ProxyFromEnvironment,
-				DialContext: (&net.Dialer{
-					Timeout:   30 * time.Second,
-					KeepAlive: 30 * time.Second,
-					DualStack: true,
-				}).DialContext,
-				ForceAttemptHTTP2:     true,
-				MaxIdleConns:          100,
-				IdleConnTimeout:       90 * time.Second,
-				TLSHandshakeTimeout:   10 * time.Second,
-				ExpectContinueTimeout: 1 * time.Second,
-				ResponseHeaderTimeout: 10 * time.Second,
-			},
-		}
+				if ce := logger.Check(zap.InfoLevel, "Received request"); ce != nil {
+					fields := []zap.Field{
+						zap.String("method", req.Method), zap.String("url", req.URL.String()),
+						zap.Any("headers", capture.redactedHeaders(req.Header)),
+					}
+					if capture.allowContentType(req.Header.Get("Content-Type")) {
+						logged, truncated, origSize := capture.captureBody(bodyBytes, req.Header.Get("Content-Encoding"))
+						fields = append(fields, zap.String("body", logged), zap.Bool("truncated", truncated), zap.Int64("orig_size", origSize))
+					}
+					ce.Write(fields...)
+				}
 
-		http.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
-			proxy.ServeHTTP(writer, request)
-		})
+				if recorder != nil {
+					attachRecordState(req, &recordState{
+						start:      time.Now(),
+						reqHeaders: req.Header.Clone(),
+						reqBody:    bodyBytes,
+					})
+				}
+			}
 
-		port := cmd.Flag("port").Value.String()
-		if cmd.Flag("listen").Value.String() == "true" {
-			logger.Info("listening on http://0.0.0.0:" + port)
-			http.ListenAndServe(":"+port, nil)
-		} else {
-			logger.Info("listening on http://localhost:" + port)
-			http.ListenAndServe("localhost:"+port, nil)
+			target, err := resolveTarget(pool, req.Host)
+			if err != nil {
+				logger.Error("no healthy upstream", zap.Error(err))
+				return
+			}
+			if pool.Bypass(req.Host) {
+				logger.Info("bypassing pool for request", zap.String("host", req.Host))
+			}
+
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = singleJoiningSlash(target.Path, req.URL.Path)
+			req.Host = target.Host
+			logger.Info("Forwarding request", zap.String("url", req.URL.String()))
+		},
+		ModifyResponse: func(res *http.Response) error {
+			ce := logger.Check(zap.InfoLevel, "Received response")
+
+			if ce != nil {
+				baseFields := []zap.Field{
+					zap.String("status", res.Status), zap.String("url", res.Request.URL.String()),
+					zap.String("method", res.Request.Method), zap.Any("headers", capture.redactedHeaders(res.Header)),
+				}
+
+				if capture.allowContentType(res.Header.Get("Content-Type")) {
+					contentEncoding := res.Header.Get("Content-Encoding")
+					res.Body = newCappedTeeReadCloser(res.Body, capture.MaxBodyBytes, func(captured []byte, truncated bool, origSize int64) {
+						logged, _, _ := capture.captureBody(captured, contentEncoding)
+						logger.Info("Received response",
+							append(baseFields, zap.String("body", logged), zap.Bool("truncated", truncated), zap.Int64("orig_size", origSize))...)
+					})
+				} else {
+					ce.Write(baseFields...)
+				}
+			}
+
+			if recorder != nil {
+				if state, ok := recordStateFrom(res.Request); ok {
+					res.Body = newCappedTeeReadCloser(res.Body, maxBodyBytes, func(captured []byte, _ bool, _ int64) {
+						recorder.Record(JournalEntry{
+							Method:          res.Request.Method,
+							URL:             res.Request.URL.String(),
+							RequestHeaders:  state.reqHeaders,
+							RequestBody:     state.reqBody,
+							ResponseStatus:  res.StatusCode,
+							ResponseHeaders: res.Header,
+							ResponseBody:    captured,
+							LatencyMS:       time.Since(state.start).Milliseconds(),
+						})
+					})
+				}
+			}
+
+			return nil
+		},
+		ErrorHandler: func(writer http.ResponseWriter, request *http.Request, e error) {
+			logger.Error("proxy error", zap.Error(e))
+		},
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+				DualStack: true,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+		},
+	}
+
+	http.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		if isWebSocketUpgrade(request) {
+			target, err := resolveTarget(pool, request.Host)
+			if err != nil {
+				logger.Error("no healthy upstream", zap.Error(err))
+				http.Error(writer, err.Error(), http.StatusBadGateway)
+				return
+			}
+			handleWebSocketUpgrade(writer, request, target, logger, maxFrameBytes)
+			return
 		}
-	},
+		proxy.ServeHTTP(writer, request)
+	})
+
+	if listenAll {
+		logger.Info("listening on http://0.0.0.0:" + port)
+		http.ListenAndServe(":"+port, nil)
+	} else {
+		logger.Info("listening on http://localhost:" + port)
+		http.ListenAndServe("localhost:"+port, nil)
+	}
+}
+
+// runForwardProxy starts the server in forward-proxy mode.
+func runForwardProxy(logger *logging.Logger, capture *BodyCaptureConfig, port string, listenAll bool) {
+	handler := newForwardProxyHandler(logger, capture)
+
+	addr := "localhost:" + port
+	if listenAll {
+		addr = ":" + port
+	}
+	logger.Info("listening (forward proxy) on http://" + addr)
+	http.ListenAndServe(addr, handler)
 }
 
 func Execute() {
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yukikun-dev/logger/internal/logging"
+	"go.uber.org/zap"
+)
+
+// ErrNoHealthyUpstream is returned by Pool.Pick when every candidate
+// upstream is currently in its cool-down period.
+var ErrNoHealthyUpstream = errors.New("pool: no healthy upstream available")
+
+type poolMember struct {
+	target *url.URL
+	typ    string
+
+	mu             sync.Mutex
+	unhealthyUntil time.Time
+}
+
+func (m *poolMember) healthy() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return time.Now().After(m.unhealthyUntil)
+}
+
+func (m *poolMember) markUnhealthy(cooldown time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unhealthyUntil = time.Now().Add(cooldown)
+}
+
+// Pool load-balances across a set of upstream targets, skipping any that the
+// background health-checker has marked unhealthy.
+type Pool struct {
+	members []*poolMember
+	counter uint64
+
+	checkURL string
+	interval time.Duration
+	cooldown time.Duration
+
+	bypassDomains         map[string]bool
+	thirdpartyOnlyDomains map[string]bool
+
+	client *http.Client
+	logger *logging.Logger
+}
+
+// NewPool builds a Pool from a loaded Config.
+func NewPool(cfg *Config, logger *logging.Logger) (*Pool, error) {
+	p := &Pool{
+		checkURL:              cfg.CheckURL,
+		interval:              cfg.CheckInterval,
+		cooldown:              cfg.CooldownPeriod,
+		bypassDomains:         toDomainSet(cfg.BypassDomains),
+		thirdpartyOnlyDomains: toDomainSet(cfg.ThirdpartyOnlyDomains),
+		client:                &http.Client{Timeout: 5 * time.Second},
+		logger:                logger,
+	}
+
+	for _, u := range cfg.Upstreams {
+		target, err := url.Parse(u.URL)
+		if err != nil {
+			return nil, err
+		}
+		p.members = append(p.members, &poolMember{target: target, typ: u.Type})
+	}
+
+	return p, nil
+}
+
+func toDomainSet(domains []string) map[string]bool {
+	set := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		set[strings.ToLower(d)] = true
+	}
+	return set
+}
+
+// Bypass reports whether requests to host should skip the pool entirely and
+// be proxied directly to themselves.
+func (p *Pool) Bypass(host string) bool {
+	return p.bypassDomains[strings.ToLower(stripPort(host))]
+}
+
+// Pick selects a healthy upstream for the given request host, honoring
+// thirdparty_only_domains. Selection is round-robin among eligible members.
+func (p *Pool) Pick(host string) (*url.URL, error) {
+	candidates := p.members
+	if p.thirdpartyOnlyDomains[strings.ToLower(stripPort(host))] {
+		candidates = filterMembers(p.members, func(m *poolMember) bool { return m.typ == "thirdparty" })
+	}
+
+	n := len(candidates)
+	if n == 0 {
+		return nil, ErrNoHealthyUpstream
+	}
+
+	start := atomic.AddUint64(&p.counter, 1)
+	for i := 0; i < n; i++ {
+		m := candidates[(int(start)+i)%n]
+		if m.healthy() {
+			return m.target, nil
+		}
+	}
+
+	return nil, ErrNoHealthyUpstream
+}
+
+// resolveTarget applies bypass routing before falling back to pool.Pick.
+func resolveTarget(pool *Pool, host string) (*url.URL, error) {
+	if pool.Bypass(host) {
+		return &url.URL{Scheme: "http", Host: host}, nil
+	}
+	return pool.Pick(host)
+}
+
+func filterMembers(members []*poolMember, keep func(*poolMember) bool) []*poolMember {
+	var out []*poolMember
+	for _, m := range members {
+		if keep(m) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func stripPort(host string) string {
+	if i := strings.LastIndex(host, ":"); i != -1 && !strings.Contains(host[i:], "]") {
+		return host[:i]
+	}
+	return host
+}
+
+// Start launches the background health-checker. It returns immediately; the
+// checker stops when ctx is canceled.
+func (p *Pool) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	go func() {
+		defer ticker.Stop()
+		p.checkAll()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.checkAll()
+			}
+		}
+	}()
+}
+
+func (p *Pool) checkAll() {
+	for _, m := range p.members {
+		go p.checkOne(m)
+	}
+}
+
+func (p *Pool) checkOne(m *poolMember) {
+	checkURL := *m.target
+	checkURL.Path = singleJoiningSlash(m.target.Path, p.checkURL)
+
+	resp, err := p.client.Get(checkURL.String())
+	if err != nil {
+		p.logger.Warn("upstream health check failed", zap.String("upstream", m.target.String()), zap.Error(err))
+		m.markUnhealthy(p.cooldown)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		p.logger.Warn("upstream health check returned non-2xx", zap.String("upstream", m.target.String()), zap.Int("status", resp.StatusCode))
+		m.markUnhealthy(p.cooldown)
+	}
+}
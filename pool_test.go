@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("url.Parse(%q): %v", raw, err)
+	}
+	return u
+}
+
+func TestPoolPickRoundRobin(t *testing.T) {
+	a := mustParseURL(t, "http://a.internal")
+	b := mustParseURL(t, "http://b.internal")
+	p := &Pool{members: []*poolMember{{target: a}, {target: b}}}
+
+	var seen []string
+	for i := 0; i < 4; i++ {
+		target, err := p.Pick("example.com")
+		if err != nil {
+			t.Fatalf("Pick: %v", err)
+		}
+		seen = append(seen, target.Host)
+	}
+
+	if seen[0] == seen[1] && seen[1] == seen[2] && seen[2] == seen[3] {
+		t.Fatalf("expected round-robin across members, got all %q", seen[0])
+	}
+}
+
+func TestPoolPickSkipsUnhealthyMembers(t *testing.T) {
+	healthy := mustParseURL(t, "http://healthy.internal")
+	unhealthy := mustParseURL(t, "http://unhealthy.internal")
+	unhealthyMember := &poolMember{target: unhealthy}
+	unhealthyMember.markUnhealthy(time.Hour)
+
+	p := &Pool{members: []*poolMember{unhealthyMember, {target: healthy}}}
+
+	for i := 0; i < 4; i++ {
+		target, err := p.Pick("example.com")
+		if err != nil {
+			t.Fatalf("Pick: %v", err)
+		}
+		if target.Host != healthy.Host {
+			t.Fatalf("Pick returned unhealthy member %q", target.Host)
+		}
+	}
+}
+
+func TestPoolPickNoHealthyUpstream(t *testing.T) {
+	unhealthyMember := &poolMember{target: mustParseURL(t, "http://down.internal")}
+	unhealthyMember.markUnhealthy(time.Hour)
+	p := &Pool{members: []*poolMember{unhealthyMember}}
+
+	if _, err := p.Pick("example.com"); err != ErrNoHealthyUpstream {
+		t.Fatalf("got err %v, want ErrNoHealthyUpstream", err)
+	}
+}
+
+func TestPoolPickThirdpartyOnlyDomains(t *testing.T) {
+	direct := mustParseURL(t, "http://direct.internal")
+	thirdparty := mustParseURL(t, "http://thirdparty.internal")
+	p := &Pool{
+		members: []*poolMember{
+			{target: direct, typ: ""},
+			{target: thirdparty, typ: "thirdparty"},
+		},
+		thirdpartyOnlyDomains: map[string]bool{"vendor.example.com": true},
+	}
+
+	for i := 0; i < 4; i++ {
+		target, err := p.Pick("vendor.example.com")
+		if err != nil {
+			t.Fatalf("Pick: %v", err)
+		}
+		if target.Host != thirdparty.Host {
+			t.Fatalf("Pick(vendor.example.com) = %q, want thirdparty-only member", target.Host)
+		}
+	}
+
+	target, err := p.Pick("other.example.com")
+	if err != nil {
+		t.Fatalf("Pick: %v", err)
+	}
+	if target.Host != direct.Host && target.Host != thirdparty.Host {
+		t.Fatalf("Pick(other.example.com) returned unexpected host %q", target.Host)
+	}
+}
+
+func TestPoolBypass(t *testing.T) {
+	p := &Pool{bypassDomains: map[string]bool{"internal.example.com": true}}
+
+	if !p.Bypass("internal.example.com:8080") {
+		t.Fatal("expected bypass to match host with port stripped")
+	}
+	if p.Bypass("other.example.com") {
+		t.Fatal("expected non-bypass domain to not match")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// JournalEntry is one recorded round-trip, written as a single JSON line by
+// Recorder and read back by the replay server. Bodies are []byte (encoded by
+// encoding/json as base64) rather than string, so non-UTF-8 bodies (gzip,
+// binary, protobuf) round-trip exactly instead of being corrupted by JSON's
+// string encoding.
+type JournalEntry struct {
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"request_headers"`
+	RequestBody     []byte      `json:"request_body"`
+	ResponseStatus  int         `json:"response_status"`
+	ResponseHeaders http.Header `json:"response_headers"`
+	ResponseBody    []byte      `json:"response_body"`
+	LatencyMS       int64       `json:"latency_ms"`
+}
+
+// Recorder appends JournalEntry values as JSON lines to a file.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecorder opens (creating/truncating) path for journaling.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file}, nil
+}
+
+// Record appends entry as one JSON line. Errors are best-effort: a failed
+// write to the journal must not interrupt the proxied response.
+func (r *Recorder) Record(entry JournalEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.file.Write(line)
+}
+
+// recordCtxKey carries a *recordState through a request's context from the
+// Director (which reads the request body) to ModifyResponse (which knows
+// the response and can append the finished journal entry).
+type recordCtxKey struct{}
+
+type recordState struct {
+	start      time.Time
+	reqHeaders http.Header
+	reqBody    []byte
+}
+
+func attachRecordState(req *http.Request, state *recordState) {
+	ctx := context.WithValue(req.Context(), recordCtxKey{}, state)
+	*req = *req.WithContext(ctx)
+}
+
+func recordStateFrom(req *http.Request) (*recordState, bool) {
+	state, ok := req.Context().Value(recordCtxKey{}).(*recordState)
+	return state, ok
+}
+
+var recordCmd = &cobra.Command{
+	Use:   "record [target URL]...",
+	Short: "run the logging proxy and additionally journal every round-trip to a file",
+	Long: `record behaves like the default reverse-proxy mode, but also writes
+each round-trip (method, URL, request/response headers and bodies, latency)
+as one JSON object per line to --record-file, for later use with replay.`,
+	PreRunE: rootCmd.PreRunE,
+	Run: func(cmd *cobra.Command, args []string) {
+		recordFile, _ := cmd.Flags().GetString("record-file")
+
+		recorder, err := NewRecorder(recordFile)
+		if err != nil {
+			panic(err)
+		}
+		defer recorder.file.Close()
+
+		runReverseProxyServer(cmd, args, recorder)
+	},
+}
+
+func init() {
+	recordCmd.Flags().AddFlagSet(rootCmd.Flags())
+	recordCmd.Flags().String("record-file", "recording.jsonl", "file to journal recorded round-trips to, one JSON object per line")
+	rootCmd.AddCommand(recordCmd)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yukikun-dev/logger/internal/logging"
+	"go.uber.org/zap"
+)
+
+// ReplayStore holds a journal loaded from a record-file and answers
+// requests by matching method+path (optionally also a request body hash).
+type ReplayStore struct {
+	entries   []JournalEntry
+	matchBody bool
+}
+
+// LoadJournal reads a record-file written by `logger record`, one
+// JournalEntry per line.
+func LoadJournal(path string) ([]JournalEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func bodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Match returns the first journal entry whose method+path (and, when
+// matchBody is set, request body hash) matches req.
+func (s *ReplayStore) Match(req *http.Request, reqBody []byte) (*JournalEntry, bool) {
+	for i := range s.entries {
+		entry := &s.entries[i]
+
+		entryURL, err := url.Parse(entry.URL)
+		if err != nil || entryURL.Path != req.URL.Path || !strings.EqualFold(entry.Method, req.Method) {
+			continue
+		}
+		if s.matchBody && bodyHash(entry.RequestBody) != bodyHash(reqBody) {
+			continue
+		}
+		return entry, true
+	}
+	return nil, false
+}
+
+// newReplayHandler serves recorded responses from store, logging and
+// returning 404 on a miss.
+func newReplayHandler(store *ReplayStore, logger *logging.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bodyBytes, _ := io.ReadAll(r.Body)
+
+		entry, ok := store.Match(r, bodyBytes)
+		if !ok {
+			logger.Warn("replay miss", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+			http.NotFound(w, r)
+			return
+		}
+
+		for key, values := range entry.ResponseHeaders {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		w.WriteHeader(entry.ResponseStatus)
+		w.Write(entry.ResponseBody)
+	})
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay --from recording.jsonl",
+	Short: "serve recorded round-trips from a journal file as a mock server",
+	Long: `replay loads a journal written by "logger record" and answers incoming
+requests by matching method+path (or, with --match=body, also request body)
+against it, returning the recorded response. Unmatched requests get a 404.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		match, _ := cmd.Flags().GetString("match")
+		port := cmd.Flag("port").Value.String()
+		listenAll := cmd.Flag("listen").Value.String() == "true"
+
+		entries, err := LoadJournal(from)
+		if err != nil {
+			return err
+		}
+
+		logger, err := logging.New(loggerOptionsFromCmd(cmd, nil))
+		if err != nil {
+			return err
+		}
+
+		store := &ReplayStore{entries: entries, matchBody: match == "body"}
+		handler := newReplayHandler(store, logger)
+
+		addr := "localhost:" + port
+		if listenAll {
+			addr = ":" + port
+		}
+		logger.Info("serving replay from " + from + " on http://" + addr)
+		return http.ListenAndServe(addr, handler)
+	},
+}
+
+func init() {
+	replayCmd.Flags().String("from", "", "journal file written by logger record to replay")
+	replayCmd.Flags().String("match", "path", "match mode: path (method+path) or body (also hash request body)")
+	replayCmd.MarkFlagRequired("from")
+	rootCmd.AddCommand(replayCmd)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yukikun-dev/logger/internal/logging"
+	"go.uber.org/zap"
+)
+
+// isWebSocketUpgrade reports whether req is a WebSocket upgrade handshake.
+func isWebSocketUpgrade(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket") &&
+		headerContainsToken(req.Header.Get("Connection"), "upgrade")
+}
+
+// isStreamingRequest reports whether req's body should be passed through
+// untouched instead of being slurped into memory for logging, because it is
+// chunked or otherwise has no known length (e.g. a long-lived upload).
+func isStreamingRequest(req *http.Request) bool {
+	for _, te := range req.TransferEncoding {
+		if strings.EqualFold(te, "chunked") {
+			return true
+		}
+	}
+	return req.ContentLength < 0
+}
+
+func headerContainsToken(header, token string) bool {
+	for _, part := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleWebSocketUpgrade dials target directly, relays the handshake, and
+// then pumps frames in both directions while logging each frame's opcode and
+// length (and, up to maxFrameBytes, its payload) via logger.
+func handleWebSocketUpgrade(w http.ResponseWriter, r *http.Request, target *url.URL, logger *logging.Logger, maxFrameBytes int64) {
+	upstreamConn, err := net.DialTimeout("tcp", target.Host, 10*time.Second)
+	if err != nil {
+		logger.Error("websocket upstream dial failed", zap.String("target", target.Host), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstreamConn.Close()
+
+	outReq := r.Clone(r.Context())
+	outReq.URL.Scheme = target.Scheme
+	outReq.URL.Host = target.Host
+	outReq.URL.Path = singleJoiningSlash(target.Path, r.URL.Path)
+	outReq.Host = target.Host
+	outReq.RequestURI = ""
+
+	if err := outReq.Write(upstreamConn); err != nil {
+		logger.Error("websocket handshake forward failed", zap.String("target", target.Host), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		logger.Error("websocket upgrade not supported: ResponseWriter does not implement Hijacker")
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	clientConn, _, err := hijacker.Hijack()
+	if err != nil {
+		logger.Error("websocket hijack failed", zap.String("target", target.Host), zap.Error(err))
+		return
+	}
+	defer clientConn.Close()
+
+	subprotocol := r.Header.Get("Sec-WebSocket-Protocol")
+	logger.Info("upgraded", zap.String("url", r.URL.String()), zap.String("subprotocol", subprotocol))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go pumpWebSocketFrames(upstreamConn, clientConn, newWSFrameParser("upstream->client", logger, maxFrameBytes), &wg)
+	go pumpWebSocketFrames(clientConn, upstreamConn, newWSFrameParser("client->upstream", logger, maxFrameBytes), &wg)
+	wg.Wait()
+
+	logger.Info("websocket closed", zap.String("url", r.URL.String()))
+}
+
+var wsOpcodeNames = map[byte]string{
+	0x0: "continuation",
+	0x1: "text",
+	0x2: "binary",
+	0x8: "close",
+	0x9: "ping",
+	0xA: "pong",
+}
+
+// pumpWebSocketFrames copies bytes unmodified from src to dst while feeding
+// them through parser to log RFC 6455 frame headers, including frames whose
+// payload spans multiple Read calls.
+func pumpWebSocketFrames(dst io.Writer, src io.Reader, parser *wsFrameParser, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			parser.feed(buf[:n])
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// wsFrameParser incrementally parses RFC 6455 frames out of a one-directional
+// byte stream that may be split arbitrarily across Read calls. It logs each
+// frame's header (opcode, fin, length) once, as soon as the header is fully
+// buffered, then skips over the remainder of that frame's payload - however
+// many reads it spans - before attempting to parse the next header.
+type wsFrameParser struct {
+	direction     string
+	logger        *logging.Logger
+	maxFrameBytes int64
+
+	header        []byte // bytes buffered toward the next frame's header
+	skipRemaining int64  // payload bytes of the current frame still to be skipped
+}
+
+func newWSFrameParser(direction string, logger *logging.Logger, maxFrameBytes int64) *wsFrameParser {
+	return &wsFrameParser{direction: direction, logger: logger, maxFrameBytes: maxFrameBytes}
+}
+
+// feed processes another chunk read from the stream. disabled logging is
+// checked once per chunk rather than per frame, since Check only tells us
+// whether info-level logging is enabled right now, not whether it was when
+// earlier bytes of a still-open frame were skipped.
+func (p *wsFrameParser) feed(chunk []byte) {
+	if p.logger.Check(zap.InfoLevel, "websocket frame") == nil {
+		return
+	}
+
+	for len(chunk) > 0 {
+		if p.skipRemaining > 0 {
+			n := int64(len(chunk))
+			if n > p.skipRemaining {
+				n = p.skipRemaining
+			}
+			chunk = chunk[n:]
+			p.skipRemaining -= n
+			continue
+		}
+
+		if len(p.header) > 0 {
+			chunk = append(p.header, chunk...)
+			p.header = nil
+		}
+		if len(chunk) < 2 {
+			p.header = append(p.header, chunk...)
+			return
+		}
+
+		fin := chunk[0]&0x80 != 0
+		opcode := chunk[0] & 0x0F
+		masked := chunk[1]&0x80 != 0
+		payloadLen := int64(chunk[1] & 0x7F)
+
+		headerLen := 2
+		switch payloadLen {
+		case 126:
+			if len(chunk) < 4 {
+				p.header = append(p.header, chunk...)
+				return
+			}
+			payloadLen = int64(chunk[2])<<8 | int64(chunk[3])
+			headerLen = 4
+		case 127:
+			if len(chunk) < 10 {
+				p.header = append(p.header, chunk...)
+				return
+			}
+			payloadLen = 0
+			for i := 0; i < 8; i++ {
+				payloadLen = payloadLen<<8 | int64(chunk[2+i])
+			}
+			headerLen = 10
+		}
+		var maskKey []byte
+		if masked {
+			if len(chunk) < headerLen+4 {
+				p.header = append(p.header, chunk...)
+				return
+			}
+			maskKey = chunk[headerLen : headerLen+4]
+			headerLen += 4
+		}
+		if len(chunk) < headerLen {
+			p.header = append(p.header, chunk...)
+			return
+		}
+
+		fields := []zap.Field{
+			zap.String("direction", p.direction),
+			zap.String("opcode", wsOpcodeName(opcode)),
+			zap.Bool("fin", fin),
+			zap.Int64("length", payloadLen),
+		}
+
+		available := chunk[headerLen:]
+		if p.maxFrameBytes > 0 && len(available) > 0 {
+			preview := available
+			if int64(len(preview)) > payloadLen {
+				preview = preview[:payloadLen]
+			}
+			if int64(len(preview)) > p.maxFrameBytes {
+				preview = preview[:p.maxFrameBytes]
+			}
+			if maskKey != nil {
+				preview = unmask(preview, maskKey)
+			}
+			fields = append(fields, zap.String("payload", fmt.Sprintf("%q", preview)))
+		}
+		p.logger.Info("websocket frame", fields...)
+
+		if int64(len(available)) >= payloadLen {
+			chunk = available[payloadLen:]
+			continue
+		}
+		p.skipRemaining = payloadLen - int64(len(available))
+		return
+	}
+}
+
+func wsOpcodeName(opcode byte) string {
+	if name, ok := wsOpcodeNames[opcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", opcode)
+}
+
+// unmask XORs payload (assumed to start at offset 0 of the frame's payload,
+// as logged previews always do) against the RFC 6455 4-byte mask key,
+// returning a new slice so the underlying stream buffer is left untouched.
+func unmask(payload, key []byte) []byte {
+	out := make([]byte, len(payload))
+	for i, b := range payload {
+		out[i] = b ^ key[i%4]
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yukikun-dev/logger/internal/logging"
+)
+
+// newTestLogger builds a logger writing JSON lines to a file under t.TempDir,
+// so tests can assert on what was actually logged.
+func newTestLogger(t *testing.T) (*logging.Logger, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.log")
+	logger, err := logging.New(logging.Options{
+		Level:  "info",
+		Format: "json",
+		Sinks:  []logging.SinkConfig{{Type: "file", Path: path}},
+	})
+	if err != nil {
+		t.Fatalf("logging.New: %v", err)
+	}
+	return logger, path
+}
+
+func countLogLines(t *testing.T, path, substr string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading log file: %v", err)
+	}
+	var n int
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.Contains(line, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWSFrameParserSplitAcrossReads(t *testing.T) {
+	logger, path := newTestLogger(t)
+
+	payload := make([]byte, 70000)
+	for i := range payload {
+		payload[i] = 0xAB
+	}
+	// fin+binary opcode, 126-length marker with an 8-byte extended length of 70000.
+	header := []byte{0x82, 127, 0, 0, 0, 0, 0, 1, 17, 112}
+	frame := append(append([]byte{}, header...), payload...)
+
+	parser := newWSFrameParser("test", logger, 16)
+	parser.feed(frame[:32*1024])
+	parser.feed(frame[32*1024:])
+
+	if got := countLogLines(t, path, "websocket frame"); got != 1 {
+		t.Fatalf("expected exactly 1 logged frame for one split payload, got %d", got)
+	}
+}
+
+func TestWSFrameParserMultipleFramesInOneRead(t *testing.T) {
+	logger, path := newTestLogger(t)
+
+	frame1 := []byte{0x81, 5, 'h', 'e', 'l', 'l', 'o'} // fin+text, 5-byte payload
+	frame2 := []byte{0x81, 3, 'b', 'y', 'e'}           // fin+text, 3-byte payload
+	chunk := append(append([]byte{}, frame1...), frame2...)
+
+	parser := newWSFrameParser("test", logger, 16)
+	parser.feed(chunk)
+
+	if got := countLogLines(t, path, "websocket frame"); got != 2 {
+		t.Fatalf("expected 2 logged frames, got %d", got)
+	}
+}
+
+func TestWSFrameParserHeaderSplitAcrossReads(t *testing.T) {
+	logger, path := newTestLogger(t)
+
+	frame := []byte{0x81, 5, 'h', 'e', 'l', 'l', 'o'}
+
+	parser := newWSFrameParser("test", logger, 16)
+	parser.feed(frame[:1]) // only the first header byte
+	parser.feed(frame[1:])
+
+	if got := countLogLines(t, path, "websocket frame"); got != 1 {
+		t.Fatalf("expected 1 logged frame once the header completes, got %d", got)
+	}
+}
+
+func TestWSFrameParserUnmasksClientFrames(t *testing.T) {
+	logger, path := newTestLogger(t)
+
+	payload := []byte("hello")
+	maskKey := []byte{0x01, 0x02, 0x03, 0x04}
+	masked := unmask(payload, maskKey) // XOR is its own inverse
+
+	frame := []byte{0x81, 0x80 | byte(len(payload))}
+	frame = append(frame, maskKey...)
+	frame = append(frame, masked...)
+
+	parser := newWSFrameParser("client->upstream", logger, 16)
+	parser.feed(frame)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading log file: %v", err)
+	}
+	if !strings.Contains(string(data), `"payload":"\"hello\""`) {
+		t.Fatalf("expected logged payload to be unmasked \"hello\", got: %s", data)
+	}
+}